@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+	"github.com/warewulf/warewulf/internal/pkg/warewulfd"
+)
+
+var pruneMaxSize string
+
+// CMD_prune implements `wwctl cache prune`.
+var CMD_prune = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict least-recently-used entries from the compressed artifact cache",
+	Long:  "Evicts the least-recently-used entries from warewulfd's compressed artifact cache until it fits under --max-size.",
+	RunE:  pruneRunE,
+}
+
+func init() {
+	CMD_prune.PersistentFlags().StringVar(&pruneMaxSize, "max-size", "1G", "target cache size (e.g. 512M, 1G)")
+}
+
+func pruneRunE(cmd *cobra.Command, args []string) error {
+	maxBytes, err := parseByteSize(pruneMaxSize)
+	if err != nil {
+		return fmt.Errorf("invalid --max-size %q: %w", pruneMaxSize, err)
+	}
+
+	conf := warewulfconf.Get()
+	return warewulfd.PruneCache(conf, maxBytes)
+}
+
+// parseByteSize parses a size like "512M" or "1G" into bytes.
+func parseByteSize(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	unit := int64(1)
+	numPart := s
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		unit = 1 << 10
+		numPart = s[:len(s)-1]
+	case 'm', 'M':
+		unit = 1 << 20
+		numPart = s[:len(s)-1]
+	case 'g', 'G':
+		unit = 1 << 30
+		numPart = s[:len(s)-1]
+	}
+
+	var value int64
+	if _, err := fmt.Sscanf(numPart, "%d", &value); err != nil {
+		return 0, err
+	}
+	return value * unit, nil
+}
@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// baseCmd is the parent for the `wwctl cache` command group. Register it
+// with the root command the same way the other top-level command groups
+// (container, overlay, node, ...) are registered.
+var baseCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage warewulfd's on-disk compressed artifact cache",
+	Long:  "Prune the cache warewulfd maintains for compressed provisioning artifacts. The built overlay image cache is not managed by this command group.",
+}
+
+func init() {
+	baseCmd.AddCommand(CMD_prune)
+}
+
+// GetCommand returns the root cobra.Command for `wwctl cache`.
+func GetCommand() *cobra.Command {
+	return baseCmd
+}
@@ -0,0 +1,86 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/warewulf/warewulf/internal/pkg/container"
+	"github.com/warewulf/warewulf/internal/pkg/kernel"
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+var (
+	repackCompress string
+	repackPin      bool
+)
+
+// CMD_repack implements `wwctl container repack`.
+var CMD_repack = &cobra.Command{
+	Use:   "repack [OPTIONS] CONTAINER",
+	Short: "Repack a container's image/initramfs artifacts with a different codec",
+	Long:  "Produces a zstd-compressed variant of an already-built container image and initramfs alongside the existing gzip one, without re-importing the container. warewulfd serves whichever codec the booting node negotiates.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  repackRunE,
+}
+
+func init() {
+	CMD_repack.PersistentFlags().StringVar(&repackCompress, "compress", "zstd", "codec to repack into, optionally with a level (e.g. zstd:19)")
+	CMD_repack.PersistentFlags().BoolVar(&repackPin, "pin", false, "pin the container to the repacked codec instead of only serving it opportunistically; only takes effect for clients whose Accept-Encoding actually accepts the pinned codec")
+	baseCmd.AddCommand(CMD_repack)
+}
+
+func repackRunE(cmd *cobra.Command, args []string) error {
+	containerName := args[0]
+
+	codec, level, err := parseCompressFlag(repackCompress)
+	if err != nil {
+		return err
+	}
+	if codec != container.CodecZstd {
+		return fmt.Errorf("unsupported --compress codec %q: only zstd is currently supported", codec)
+	}
+
+	opts := container.RepackOptions{Level: level}
+	if err := container.Repack(containerName, opts); err != nil {
+		return fmt.Errorf("could not repack container %s: %w", containerName, err)
+	}
+
+	// Also repack the initramfs already built for this container, if any:
+	// that's the artifact the request was actually aimed at speeding up,
+	// since it's decompressed during early boot rather than by an
+	// already-running userspace.
+	if _, kver, err := kernel.FindKernel(container.RootFsDir(containerName)); err == nil {
+		if err := container.RepackInitramfs(containerName, kver, opts); err != nil {
+			return fmt.Errorf("could not repack initramfs for container %s kernel %s: %w", containerName, kver, err)
+		}
+	} else {
+		wwlog.Warn("container %s: no kernel found, skipping initramfs repack: %s", containerName, err)
+	}
+
+	if repackPin {
+		if err := container.SetPreferredCodec(containerName, codec); err != nil {
+			return fmt.Errorf("could not pin %s to codec %s: %w", containerName, codec, err)
+		}
+	}
+	return nil
+}
+
+// parseCompressFlag parses a --compress value of the form "zstd" or
+// "zstd:19" into a Codec and an optional level (0 if unspecified).
+func parseCompressFlag(s string) (container.Codec, int, error) {
+	name, levelStr, hasLevel := strings.Cut(s, ":")
+	codec := container.ParseCodec(name)
+
+	if !hasLevel {
+		return codec, 0, nil
+	}
+
+	level, err := strconv.Atoi(levelStr)
+	if err != nil {
+		return codec, 0, fmt.Errorf("invalid compression level %q", levelStr)
+	}
+	return codec, level, nil
+}
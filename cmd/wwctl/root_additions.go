@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/warewulf/warewulf/cmd/wwctl/cache"
+	"github.com/warewulf/warewulf/cmd/wwctl/status"
+)
+
+// init registers the `wwctl cache` and `wwctl status` command groups
+// added alongside the compression-cache and live-status work. It's kept
+// in its own file rather than folded into root.go's own init to keep
+// that diff small and easy to review independently of the rest of
+// root.go's command wiring.
+func init() {
+	rootCmd.AddCommand(cache.GetCommand())
+	rootCmd.AddCommand(status.GetCommand())
+}
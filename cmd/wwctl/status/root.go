@@ -0,0 +1,23 @@
+package status
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// baseCmd is the parent for the `wwctl status` command group. Register it
+// with the root command the same way the other top-level command groups
+// (container, overlay, node, ...) are registered.
+var baseCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Observe node provisioning as it happens",
+	Long:  "Stream or inspect the provisioning stage transitions warewulfd records for booting nodes.",
+}
+
+func init() {
+	baseCmd.AddCommand(CMD_watch)
+}
+
+// GetCommand returns the root cobra.Command for `wwctl status`.
+func GetCommand() *cobra.Command {
+	return baseCmd
+}
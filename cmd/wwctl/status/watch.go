@@ -0,0 +1,86 @@
+package status
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+	"github.com/warewulf/warewulf/internal/pkg/provisionstatus"
+)
+
+var (
+	watchNode  string
+	watchStage string
+)
+
+// CMD_watch implements `wwctl status watch`.
+var CMD_watch = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream live provisioning stage transitions",
+	Long:  "Connects to warewulfd's /status/events stream and renders provisioning stage transitions as a live table (à la `kubectl get -w`), optionally filtered by --node and --stage.",
+	RunE:  watchRunE,
+}
+
+func init() {
+	CMD_watch.PersistentFlags().StringVar(&watchNode, "node", "", "only show events for this node")
+	CMD_watch.PersistentFlags().StringVar(&watchStage, "stage", "", "only show events for this provisioning stage")
+}
+
+func watchRunE(cmd *cobra.Command, args []string) error {
+	conf := warewulfconf.Get()
+
+	endpoint := url.URL{
+		Scheme: "http",
+		Host:   "localhost:" + strconv.Itoa(conf.Warewulf.Port),
+		Path:   "/status/events",
+	}
+	q := endpoint.Query()
+	if watchNode != "" {
+		q.Set("node", watchNode)
+	}
+	if watchStage != "" {
+		q.Set("stage", watchStage)
+	}
+	endpoint.RawQuery = q.Encode()
+
+	resp, err := http.Get(endpoint.String())
+	if err != nil {
+		return fmt.Errorf("could not connect to warewulfd: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("warewulfd returned %s for %s", resp.Status, endpoint.String())
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tNODE\tSTAGE\tSTATUS\tARTIFACT")
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+
+		var e provisionstatus.Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Format("15:04:05"), e.NodeId, e.Stage, e.Status, e.Artifact)
+		tw.Flush()
+	}
+	return scanner.Err()
+}
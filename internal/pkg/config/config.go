@@ -0,0 +1,69 @@
+// Package config loads and exposes warewulf.conf, the controller's main
+// configuration file.
+package config
+
+import (
+	"sync"
+)
+
+// PathsConf holds the filesystem locations warewulfd and wwctl read and
+// write under, derived from how Warewulf was installed/configured.
+type PathsConf struct {
+	// Sysconfdir is the root of Warewulf's configuration tree (nodes.conf,
+	// overlays, ipxe templates, ...), typically /etc; Warewulf's files live
+	// under its warewulf/ subdirectory.
+	Sysconfdir string `yaml:"sysconfdir,omitempty"`
+	// LocalStateDir is the root of Warewulf's variable/runtime state
+	// (caches, built overlays, ...), typically /var/lib; Warewulf's state
+	// lives under its warewulf/ subdirectory.
+	LocalStateDir string `yaml:"localstatedir,omitempty"`
+}
+
+// WarewulfConf holds the `warewulf:` section of warewulf.conf: the
+// settings for warewulfd's provisioning behavior.
+type WarewulfConf struct {
+	// Port is the TCP port warewulfd listens on for provisioning requests.
+	Port int `yaml:"port,omitempty"`
+	// Secure requires runtime-stage and overlay requests to originate from
+	// a privileged source port.
+	Secure bool `yaml:"secure,omitempty"`
+	// AutobuildOverlays builds a node's overlay on demand when it isn't
+	// already cached, rather than requiring `wwctl overlay build` to have
+	// been run ahead of time.
+	AutobuildOverlays bool `yaml:"autobuild overlays,omitempty"`
+	// CompressStages overrides, per provisioning stage, which transfer
+	// encoding ProvisionSend prefers when negotiating Accept-Encoding.
+	// A stage with no entry falls back to the built-in default; an
+	// explicit "identity" or empty entry disables compression for that
+	// stage.
+	CompressStages map[string]string `yaml:"compress stages,omitempty"`
+	// OverlayBuildWorkers bounds how many overlay builds the overlay
+	// build coordinator runs concurrently. Zero (the default) uses one
+	// worker per CPU.
+	OverlayBuildWorkers int `yaml:"overlay build workers,omitempty"`
+}
+
+// ControllerConf is the top-level structure of warewulf.conf.
+type ControllerConf struct {
+	Paths    PathsConf    `yaml:"paths,omitempty"`
+	Warewulf WarewulfConf `yaml:"warewulf,omitempty"`
+}
+
+var (
+	cached     *ControllerConf
+	cachedOnce sync.Once
+)
+
+// Get returns the process-wide ControllerConf, lazily initialized to its
+// zero-value defaults. Callers needing an explicitly loaded configuration
+// should prefer whatever load path populates this during daemon/wwctl
+// startup; Get exists for code paths (like command RunE functions) that
+// only need read access to the already-loaded configuration.
+func Get() *ControllerConf {
+	cachedOnce.Do(func() {
+		if cached == nil {
+			cached = &ControllerConf{}
+		}
+	})
+	return cached
+}
@@ -0,0 +1,104 @@
+package container
+
+import (
+	"os"
+	"strings"
+)
+
+// Codec identifies how a container/initramfs artifact on disk is
+// compressed.
+type Codec string
+
+const (
+	// CodecGzip is the historical default: the unsuffixed artifact
+	// produced by the existing build/import pipeline.
+	CodecGzip Codec = "gzip"
+	CodecZstd Codec = "zstd"
+)
+
+// codecSuffix maps a Codec to the filename suffix `repack` writes its
+// variant under.
+var codecSuffix = map[Codec]string{
+	CodecZstd: ".zst",
+}
+
+// ParseCodec maps a negotiated transfer-encoding token (as returned by
+// the warewulfd compression negotiator) to a Codec, defaulting to
+// CodecGzip for anything it doesn't recognize as zstd.
+func ParseCodec(encoding string) Codec {
+	if encoding == string(CodecZstd) {
+		return CodecZstd
+	}
+	return CodecGzip
+}
+
+// ImageFileFor returns the path to containerName's image artifact built
+// with codec, falling back to the default gzip artifact if that codec
+// hasn't actually been repacked for this container yet. codec is the
+// caller's responsibility to resolve (negotiated Accept-Encoding, a
+// pinned codec from SetPreferredCodec, or some combination) before
+// calling this — ImageFileFor itself has no opinion on how codec was
+// chosen.
+func ImageFileFor(containerName string, codec Codec) string {
+	base := ImageFile(containerName)
+
+	if suffix, ok := codecSuffix[codec]; ok {
+		if candidate := base + suffix; isFile(candidate) {
+			return candidate
+		}
+	}
+	return base
+}
+
+// InitramfsBootPathFor is the codec-aware counterpart of
+// InitramfsBootPath: it returns the initramfs built with codec for kver,
+// falling back to the default gzip initramfs if that codec hasn't been
+// repacked yet.
+func InitramfsBootPathFor(containerName, kver string, codec Codec) (string, error) {
+	base, err := InitramfsBootPath(containerName, kver)
+	if err != nil {
+		return "", err
+	}
+
+	if suffix, ok := codecSuffix[codec]; ok {
+		if candidate := base + suffix; isFile(candidate) {
+			return candidate, nil
+		}
+	}
+	return base, nil
+}
+
+// codecPinFile is where SetPreferredCodec records an operator's pinned
+// codec for a container, so that choice survives across requests instead
+// of only being whatever the negotiator opportunistically probed for.
+func codecPinFile(containerName string) string {
+	return ImageFile(containerName) + ".codec"
+}
+
+// SetPreferredCodec pins containerName's preferred codec, which is used
+// in place of the negotiated codec for clients that accept it (see
+// PreferredCodec). It backs the --pin flag of `wwctl container repack`.
+func SetPreferredCodec(containerName string, codec Codec) error {
+	return os.WriteFile(codecPinFile(containerName), []byte(codec), 0644)
+}
+
+// PreferredCodec returns the codec pinned for containerName via
+// SetPreferredCodec, and whether a pin exists at all.
+//
+// A pin is a hint, not an override: the caller (ProvisionSend) still has
+// to confirm the requesting client actually advertised support for the
+// pinned codec via Accept-Encoding before using it — otherwise a pin
+// toward zstd would serve zstd to a client that only ever said it could
+// decode gzip.
+func PreferredCodec(containerName string) (Codec, bool) {
+	data, err := os.ReadFile(codecPinFile(containerName))
+	if err != nil {
+		return "", false
+	}
+	return Codec(strings.TrimSpace(string(data))), true
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,94 @@
+package container
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// RepackOptions controls how Repack re-encodes an existing artifact.
+type RepackOptions struct {
+	// Level is the zstd compression level (klauspost/compress/zstd
+	// encoder level, e.g. zstd.SpeedDefault). Zero means "use the
+	// library default".
+	Level int
+	// Long enables the long-distance-matching window, trading memory
+	// for better ratios on large (multi-hundred-MB) initramfs/container
+	// artifacts.
+	Long bool
+}
+
+// Repack materializes a zstd-compressed variant of containerName's image
+// artifact alongside the existing gzip one, without re-importing the
+// container. It backs `wwctl container repack`.
+func Repack(containerName string, opts RepackOptions) error {
+	src := ImageFile(containerName)
+	return repackFile(src, src+codecSuffix[CodecZstd], opts)
+}
+
+// RepackInitramfs is the initramfs counterpart of Repack: it produces a
+// zstd-compressed variant of the initramfs already built for kver.
+func RepackInitramfs(containerName, kver string, opts RepackOptions) error {
+	src, err := InitramfsBootPath(containerName, kver)
+	if err != nil {
+		return err
+	}
+	return repackFile(src, src+codecSuffix[CodecZstd], opts)
+}
+
+func repackFile(src, dst string, opts RepackOptions) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("could not open %s for repacking: %w", src, err)
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if gz, err := gzip.NewReader(in); err == nil {
+		reader = gz
+		defer gz.Close()
+	} else if _, seekErr := in.Seek(0, io.SeekStart); seekErr == nil {
+		// Not gzip-compressed (e.g. already a raw image); repack the
+		// raw bytes directly.
+		reader = in
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".repack-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var zstdOpts []zstd.EOption
+	if opts.Level > 0 {
+		zstdOpts = append(zstdOpts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level)))
+	}
+	if opts.Long {
+		zstdOpts = append(zstdOpts, zstd.WithWindowSize(1<<27))
+	}
+
+	zw, err := zstd.NewWriter(tmp, zstdOpts...)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := io.Copy(zw, reader); err != nil {
+		zw.Close()
+		tmp.Close()
+		return fmt.Errorf("could not repack %s: %w", src, err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
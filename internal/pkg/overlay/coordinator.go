@@ -0,0 +1,255 @@
+package overlay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/warewulf/warewulf/internal/pkg/node"
+	"github.com/warewulf/warewulf/internal/pkg/util"
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// BuildFunc performs the actual (expensive) overlay render for a node and
+// returns the path to the rendered overlay image. It is supplied by the
+// caller (warewulfd) since building an overlay needs far more context
+// than this package tracks.
+type BuildFunc func(n node.Node, context string, overlayNames []string) (string, error)
+
+// Coordinator deduplicates concurrent overlay build requests that share
+// the same inputs, bounds how many builds run at once, and caches built
+// images on disk keyed by a hash of their inputs, so a boot storm of
+// identical nodes only pays for one build instead of N.
+type Coordinator struct {
+	build    BuildFunc
+	cacheDir string
+
+	group singleflight.Group
+
+	semMu sync.RWMutex
+	sem   chan struct{}
+
+	hits, misses, evictions, inflight int64
+}
+
+// NewCoordinator returns a Coordinator that caches rendered overlays under
+// cacheDir and runs at most workers builds concurrently, using build to
+// render on a cache miss.
+func NewCoordinator(build BuildFunc, cacheDir string, workers int) *Coordinator {
+	c := &Coordinator{
+		build:    build,
+		cacheDir: cacheDir,
+	}
+	c.Resize(workers)
+	return c
+}
+
+// Resize changes how many builds the coordinator allows to run
+// concurrently, taking effect for builds started after the call
+// returns. It is safe to call from warewulfd whenever warewulf.conf is
+// reloaded.
+func (c *Coordinator) Resize(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	c.semMu.Lock()
+	c.sem = make(chan struct{}, workers)
+	c.semMu.Unlock()
+}
+
+// currentSem returns the live worker semaphore. Callers must acquire and
+// release the SAME channel value they got back here rather than
+// re-reading c.sem, since a concurrent Resize swaps it out.
+func (c *Coordinator) currentSem() chan struct{} {
+	c.semMu.RLock()
+	defer c.semMu.RUnlock()
+	return c.sem
+}
+
+// Stats is a snapshot of the coordinator's cache counters, suitable for
+// exposing as Prometheus gauges.
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Inflight  int64
+	Evictions int64
+}
+
+// Stats returns a snapshot of the coordinator's current counters.
+func (c *Coordinator) Stats() Stats {
+	return Stats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Inflight:  atomic.LoadInt64(&c.inflight),
+		Evictions: atomic.LoadInt64(&c.evictions),
+	}
+}
+
+// Build returns the path to a cached, up-to-date overlay image for n,
+// building it (at most once across concurrent callers) when inputHash no
+// longer matches what is cached.
+func (c *Coordinator) Build(n node.Node, context string, overlayNames []string, inputHash []byte) (string, error) {
+	key := hex.EncodeToString(inputHash)
+	cachedPath := path.Join(c.cacheDir, key+".img")
+
+	if util.IsFile(cachedPath) {
+		atomic.AddInt64(&c.hits, 1)
+		return cachedPath, nil
+	}
+
+	result, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// Re-check under the singleflight key: another goroutine may have
+		// finished the build while we were waiting to enter Do.
+		if util.IsFile(cachedPath) {
+			atomic.AddInt64(&c.hits, 1)
+			return cachedPath, nil
+		}
+
+		atomic.AddInt64(&c.misses, 1)
+		atomic.AddInt64(&c.inflight, 1)
+		defer atomic.AddInt64(&c.inflight, -1)
+
+		sem := c.currentSem()
+		sem <- struct{}{}
+		defer func() { <-sem }()
+
+		built, err := c.build(n, context, overlayNames)
+		if err != nil {
+			return "", err
+		}
+
+		if err := c.store(built, cachedPath); err != nil {
+			wwlog.Error("overlay cache: could not persist %s as %s: %s", built, cachedPath, err)
+			return built, nil
+		}
+		return cachedPath, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
+
+// store makes built available at cachedPath, creating the cache
+// directory as needed. It prefers a hardlink (cheap, same filesystem)
+// and falls back to a copy.
+func (c *Coordinator) store(built, cachedPath string) error {
+	if err := os.MkdirAll(path.Dir(cachedPath), 0755); err != nil {
+		return err
+	}
+
+	if built == cachedPath {
+		return nil
+	}
+
+	if err := os.Link(built, cachedPath); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(built)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(path.Dir(cachedPath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachedPath)
+}
+
+// Evict removes every cached entry whose hash is not in liveKeys and
+// reports how many were removed. The coordinator's cache is already
+// self-invalidating via the input hash, so eviction here is about
+// reclaiming disk space for keys no longer referenced by any node.
+func (c *Coordinator) Evict(liveKeys map[string]bool) (int, error) {
+	entries, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	evicted := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		key := e.Name()
+		if len(key) > 4 && key[len(key)-4:] == ".img" {
+			key = key[:len(key)-4]
+		}
+		if liveKeys[key] {
+			continue
+		}
+		if err := os.Remove(path.Join(c.cacheDir, e.Name())); err != nil {
+			wwlog.Error("overlay cache: could not evict %s: %s", e.Name(), err)
+			continue
+		}
+		evicted++
+	}
+	if evicted > 0 {
+		atomic.AddInt64(&c.evictions, int64(evicted))
+	}
+	return evicted, nil
+}
+
+// InputHash hashes the pieces that determine whether a cached overlay
+// image is still valid: the node identity/context/overlay list, plus the
+// mtime+size of every file under each path in contentPaths (overlay
+// source directories, the node definition file, the container image,
+// etc). Directories are walked recursively so an edit to a file nested
+// inside an overlay invalidates the cache even though the directory's
+// own mtime may not change.
+func InputHash(nodeId, context string, overlayNames []string, contentPaths []string) []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "node:%s\ncontext:%s\noverlays:%v\n", nodeId, context, overlayNames)
+	for _, p := range contentPaths {
+		hashPath(h, p)
+	}
+	return h.Sum(nil)
+}
+
+// hashPath stats p, recursing into it if it is a directory, and writes a
+// stable representation of what it finds to h. Missing paths still
+// contribute their absence so a later creation changes the hash.
+func hashPath(h hash.Hash, p string) {
+	info, err := os.Stat(p)
+	if err != nil {
+		fmt.Fprintf(h, "missing:%s\n", p)
+		return
+	}
+
+	if !info.IsDir() {
+		fmt.Fprintf(h, "path:%s:%d:%d\n", p, info.Size(), info.ModTime().UnixNano())
+		return
+	}
+
+	_ = filepath.Walk(p, func(sub string, subInfo os.FileInfo, err error) error {
+		if err != nil || subInfo.IsDir() {
+			return nil
+		}
+		fmt.Fprintf(h, "path:%s:%d:%d\n", sub, subInfo.Size(), subInfo.ModTime().UnixNano())
+		return nil
+	})
+}
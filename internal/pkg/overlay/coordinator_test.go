@@ -0,0 +1,97 @@
+package overlay
+
+import (
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/warewulf/warewulf/internal/pkg/node"
+)
+
+func TestCoordinatorBuildCachesOnSubsequentCalls(t *testing.T) {
+	cacheDir := t.TempDir()
+	srcDir := t.TempDir()
+	src := path.Join(srcDir, "overlay.img")
+	if err := os.WriteFile(src, []byte("overlay"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int64
+	c := NewCoordinator(func(n node.Node, context string, overlayNames []string) (string, error) {
+		atomic.AddInt64(&calls, 1)
+		return src, nil
+	}, cacheDir, 2)
+
+	hash := []byte("fixed-hash")
+	first, err := c.Build(node.Node{}, "system", nil, hash)
+	if err != nil {
+		t.Fatalf("first Build: %s", err)
+	}
+	second, err := c.Build(node.Node{}, "system", nil, hash)
+	if err != nil {
+		t.Fatalf("second Build: %s", err)
+	}
+	if first != second {
+		t.Fatalf("cached path changed between calls: %q != %q", first, second)
+	}
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Fatalf("build function called %d times, want 1 (second call should hit cache)", calls)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("Stats = %+v, want 1 hit and 1 miss", stats)
+	}
+}
+
+func TestCoordinatorBuildCoalescesConcurrentCallsForSameHash(t *testing.T) {
+	cacheDir := t.TempDir()
+	srcDir := t.TempDir()
+	src := path.Join(srcDir, "overlay.img")
+	if err := os.WriteFile(src, []byte("overlay"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int64
+	release := make(chan struct{})
+	c := NewCoordinator(func(n node.Node, context string, overlayNames []string) (string, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return src, nil
+	}, cacheDir, 4)
+
+	hash := []byte("shared-hash")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Build(node.Node{}, "system", nil, hash); err != nil {
+				t.Errorf("Build: %s", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Fatalf("build function called %d times for identical concurrent requests, want 1", got)
+	}
+}
+
+func TestCoordinatorResizeAppliesToSubsequentBuilds(t *testing.T) {
+	cacheDir := t.TempDir()
+	c := NewCoordinator(func(n node.Node, context string, overlayNames []string) (string, error) {
+		return "", nil
+	}, cacheDir, 1)
+
+	c.Resize(3)
+	if cap(c.currentSem()) != 3 {
+		t.Fatalf("semaphore capacity = %d, want 3 after Resize", cap(c.currentSem()))
+	}
+}
@@ -0,0 +1,29 @@
+package overlay
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ServeMetrics renders the coordinator's cache counters in Prometheus
+// text exposition format for the warewulfd /metrics endpoint.
+func (c *Coordinator) ServeMetrics(w http.ResponseWriter, req *http.Request) {
+	stats := c.Stats()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP warewulf_overlay_build_cache_hits_total Overlay build cache hits.\n")
+	fmt.Fprintf(w, "# TYPE warewulf_overlay_build_cache_hits_total counter\n")
+	fmt.Fprintf(w, "warewulf_overlay_build_cache_hits_total %d\n", stats.Hits)
+
+	fmt.Fprintf(w, "# HELP warewulf_overlay_build_cache_misses_total Overlay build cache misses.\n")
+	fmt.Fprintf(w, "# TYPE warewulf_overlay_build_cache_misses_total counter\n")
+	fmt.Fprintf(w, "warewulf_overlay_build_cache_misses_total %d\n", stats.Misses)
+
+	fmt.Fprintf(w, "# HELP warewulf_overlay_build_inflight Overlay builds currently running or waiting for a worker.\n")
+	fmt.Fprintf(w, "# TYPE warewulf_overlay_build_inflight gauge\n")
+	fmt.Fprintf(w, "warewulf_overlay_build_inflight %d\n", stats.Inflight)
+
+	fmt.Fprintf(w, "# HELP warewulf_overlay_build_cache_evictions_total Overlay build cache entries evicted.\n")
+	fmt.Fprintf(w, "# TYPE warewulf_overlay_build_cache_evictions_total counter\n")
+	fmt.Fprintf(w, "warewulf_overlay_build_cache_evictions_total %d\n", stats.Evictions)
+}
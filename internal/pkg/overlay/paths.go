@@ -0,0 +1,14 @@
+package overlay
+
+import (
+	"path"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+)
+
+// OverlaySourceDir returns the directory an overlay's source templates
+// and files are read from, used by the build coordinator to know what to
+// stat when computing a cache key.
+func OverlaySourceDir(conf *warewulfconf.ControllerConf, name string) string {
+	return path.Join(conf.Paths.Sysconfdir, "warewulf/overlays", name)
+}
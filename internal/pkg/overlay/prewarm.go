@@ -0,0 +1,48 @@
+package overlay
+
+import (
+	"time"
+
+	"github.com/warewulf/warewulf/internal/pkg/node"
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// NodeInputs describes what a node needs rebuilt: the overlay context (or
+// an explicit overlay list) and the paths whose mtime+size feed the
+// content hash.
+type NodeInputs struct {
+	Context      string
+	OverlayNames []string
+	ContentPaths []string
+}
+
+// Prewarm periodically rebuilds overlays for every node returned by
+// listNodes, so that the first PXE request after a node/overlay change is
+// served from a warm cache instead of blocking on a build. It runs until
+// stop is closed; warewulfd starts one Prewarm goroutine per overlay
+// context.
+func (c *Coordinator) Prewarm(listNodes func() ([]node.Node, error), inputsFor func(node.Node) NodeInputs, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			nodes, err := listNodes()
+			if err != nil {
+				wwlog.Error("overlay prewarm: could not list nodes: %s", err)
+				continue
+			}
+
+			for _, n := range nodes {
+				inputs := inputsFor(n)
+				hash := InputHash(n.Id(), inputs.Context, inputs.OverlayNames, inputs.ContentPaths)
+				if _, err := c.Build(n, inputs.Context, inputs.OverlayNames, hash); err != nil {
+					wwlog.Error("overlay prewarm: could not build overlay for %s: %s", n.Id(), err)
+				}
+			}
+		}
+	}
+}
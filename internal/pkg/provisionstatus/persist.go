@@ -0,0 +1,56 @@
+package provisionstatus
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// SaveToDisk writes the current ring contents to path as JSON, so that
+// recent boot history survives a warewulfd restart.
+func (r *Ring) SaveToDisk(path string) error {
+	events := r.Since(time.Time{})
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".provisionstatus-*")
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	if err := enc.Encode(events); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFromDisk replays events previously persisted with SaveToDisk back
+// into the ring. It is a no-op (not an error) if path does not exist.
+func (r *Ring) LoadFromDisk(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		wwlog.Warn("provisionstatus: could not parse persisted history %s: %s", path, err)
+		return nil
+	}
+
+	for _, e := range events {
+		r.Record(e)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package provisionstatus tracks per-node provisioning stage transitions
+// in memory so operators can observe a boot in progress instead of only
+// reconstructing it after the fact from logs.
+package provisionstatus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// Event is a single stage transition recorded for a node.
+type Event struct {
+	Time      time.Time `json:"time"`
+	NodeId    string    `json:"node_id"`
+	Stage     string    `json:"stage"`
+	Status    string    `json:"status"`
+	BytesSent int64     `json:"bytes_sent,omitempty"`
+	SourceIP  string    `json:"source_ip,omitempty"`
+	Artifact  string    `json:"artifact,omitempty"`
+}
+
+// ringSize bounds memory use; at several hundred bytes per event this
+// comfortably covers a large boot storm without unbounded growth.
+const ringSize = 8192
+
+// Ring is an in-memory, fixed-capacity history of provisioning events
+// along with a fan-out to live subscribers (e.g. SSE clients).
+type Ring struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	filled bool
+
+	subs map[chan Event]struct{}
+}
+
+// NewRing returns an empty Ring.
+func NewRing() *Ring {
+	return &Ring{
+		events: make([]Event, ringSize),
+		subs:   make(map[chan Event]struct{}),
+	}
+}
+
+// Default is the process-wide ring used by warewulfd. It is a package
+// variable (rather than a constructor argument threaded through the HTTP
+// handlers) to match the rest of warewulfd's singleton daemon state.
+var Default = NewRing()
+
+// Record appends an event to the ring, overwriting the oldest entry once
+// full, and publishes it to any live subscribers.
+func (r *Ring) Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.filled = true
+	}
+	subs := make([]chan Event, 0, len(r.subs))
+	for ch := range r.subs {
+		subs = append(subs, ch)
+	}
+	r.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- e:
+		default:
+			wwlog.Warn("provisionstatus: subscriber channel full, dropping event for %s", e.NodeId)
+		}
+	}
+}
+
+// Record publishes an event on the default ring.
+func Record(e Event) {
+	Default.Record(e)
+}
+
+// Since returns all recorded events with Time after since, oldest first.
+func (r *Ring) Since(since time.Time) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ordered []Event
+	if r.filled {
+		ordered = append(ordered, r.events[r.next:]...)
+	}
+	ordered = append(ordered, r.events[:r.next]...)
+
+	var out []Event
+	for _, e := range ordered {
+		if e.Time.After(since) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Subscribe registers a new live subscriber and returns a channel of
+// future events along with a function to unregister it. Callers must
+// invoke the returned cancel function when done to avoid leaking the
+// channel.
+func (r *Ring) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 256)
+
+	r.mu.Lock()
+	r.subs[ch] = struct{}{}
+	r.mu.Unlock()
+
+	cancel := func() {
+		r.mu.Lock()
+		delete(r.subs, ch)
+		r.mu.Unlock()
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// Matches reports whether an event satisfies the given node/stage
+// filters, where an empty filter matches anything.
+func (e Event) Matches(node, stage string) bool {
+	if node != "" && e.NodeId != node {
+		return false
+	}
+	if stage != "" && e.Stage != stage {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package provisionstatus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRingSinceOrdersOldestFirstAndFilters(t *testing.T) {
+	r := NewRing()
+
+	base := time.Now()
+	r.Record(Event{Time: base.Add(1 * time.Second), NodeId: "n1", Stage: "system"})
+	r.Record(Event{Time: base.Add(2 * time.Second), NodeId: "n2", Stage: "runtime"})
+	r.Record(Event{Time: base.Add(3 * time.Second), NodeId: "n1", Stage: "runtime"})
+
+	all := r.Since(base)
+	if len(all) != 3 {
+		t.Fatalf("Since(base) = %d events, want 3", len(all))
+	}
+	if all[0].NodeId != "n1" || all[1].NodeId != "n2" || all[2].NodeId != "n1" {
+		t.Fatalf("Since(base) not in record order: %+v", all)
+	}
+
+	later := r.Since(base.Add(2 * time.Second))
+	if len(later) != 1 || later[0].Stage != "runtime" || later[0].NodeId != "n1" {
+		t.Fatalf("Since(base+2s) = %+v, want only the last event", later)
+	}
+}
+
+func TestRingSinceWrapsAroundOnceFull(t *testing.T) {
+	r := NewRing()
+	r.events = make([]Event, 3)
+
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		r.Record(Event{Time: base.Add(time.Duration(i) * time.Second), NodeId: "n1"})
+	}
+
+	got := r.Since(time.Time{})
+	if len(got) != 3 {
+		t.Fatalf("Since after wraparound = %d events, want 3 (ring capacity)", len(got))
+	}
+	for i, e := range got {
+		wantOffset := 2 + i
+		if !e.Time.Equal(base.Add(time.Duration(wantOffset) * time.Second)) {
+			t.Fatalf("event %d = %v, want offset %ds from base", i, e.Time, wantOffset)
+		}
+	}
+}
+
+func TestRingSubscribeReceivesFutureEventsOnly(t *testing.T) {
+	r := NewRing()
+	r.Record(Event{NodeId: "before-subscribe"})
+
+	ch, cancel := r.Subscribe()
+	defer cancel()
+
+	r.Record(Event{NodeId: "after-subscribe"})
+
+	select {
+	case e := <-ch:
+		if e.NodeId != "after-subscribe" {
+			t.Fatalf("got event %q, want %q", e.NodeId, "after-subscribe")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for subscribed event")
+	}
+
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected second event delivered: %+v", e)
+	default:
+	}
+}
+
+func TestEventMatches(t *testing.T) {
+	e := Event{NodeId: "n1", Stage: "system"}
+
+	cases := []struct {
+		node, stage string
+		want        bool
+	}{
+		{"", "", true},
+		{"n1", "", true},
+		{"", "system", true},
+		{"n1", "system", true},
+		{"n2", "", false},
+		{"", "runtime", false},
+	}
+	for _, c := range cases {
+		if got := e.Matches(c.node, c.stage); got != c.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", c.node, c.stage, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package warewulfd
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+	"github.com/warewulf/warewulf/internal/pkg/util"
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// encIdentity, encGzip and encZstd are the transfer-encoding tokens
+// ProvisionSend understands, matching the tokens used in Accept-Encoding.
+const (
+	encIdentity = "identity"
+	encGzip     = "gzip"
+	encZstd     = "zstd"
+)
+
+// defaultStageCodecs lists, per provisioning stage, the encodings the
+// server is willing to serve in order of preference when warewulf.conf
+// doesn't override it. Stages not listed here only ever serve identity.
+var defaultStageCodecs = map[string][]string{
+	"initramfs": {encZstd, encGzip},
+	"container": {encZstd, encGzip},
+	"system":    {encZstd, encGzip},
+	"runtime":   {encZstd, encGzip},
+	"ipxe":      {encGzip},
+	"efiboot":   {encGzip},
+}
+
+// stageCodecPreference returns the ordered list of encodings the server
+// is willing to serve for stage, preferring an operator override from
+// warewulf.conf's `compress stages` map (stage name -> preferred
+// encoding) over the built-in default.
+func stageCodecPreference(conf *warewulfconf.ControllerConf, stage string) []string {
+	if conf != nil && conf.Warewulf.CompressStages != nil {
+		if preferred, ok := conf.Warewulf.CompressStages[stage]; ok {
+			if preferred == "" || preferred == encIdentity {
+				return nil
+			}
+			return []string{preferred, encGzip}
+		}
+	}
+	return defaultStageCodecs[stage]
+}
+
+// encodingExt maps an encoding token to the suffix used for both
+// pre-generated sibling files (e.g. "foo.img.zst") and the compressed
+// cache.
+var encodingExt = map[string]string{
+	encGzip: "gz",
+	encZstd: "zst",
+}
+
+type acceptedEncoding struct {
+	name string
+	q    float64
+}
+
+// parseAcceptEncoding parses an Accept-Encoding header value into a list
+// of encodings ordered from most to least preferred, honoring q-values.
+// Unparsable entries are ignored rather than rejecting the request.
+func parseAcceptEncoding(header string) []acceptedEncoding {
+	if header == "" {
+		return nil
+	}
+
+	var accepted []acceptedEncoding
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name := part
+		q := 1.0
+		if semi := strings.Index(part, ";"); semi != -1 {
+			name = strings.TrimSpace(part[:semi])
+			for _, param := range strings.Split(part[semi+1:], ";") {
+				param = strings.TrimSpace(param)
+				if qv, ok := strings.CutPrefix(param, "q="); ok {
+					if parsed, err := strconv.ParseFloat(qv, 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+		accepted = append(accepted, acceptedEncoding{name: strings.ToLower(name), q: q})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+	return accepted
+}
+
+// negotiateEncoding picks the transfer encoding to use for a given stage,
+// preferring whatever the client asked for via Accept-Encoding among the
+// encodings the server is willing to serve for that stage, and falling
+// back to identity when nothing matches. Among entries the client gave
+// equal q-values, the server's own preference order for the stage decides.
+func negotiateEncoding(conf *warewulfconf.ControllerConf, stage string, acceptHeader string) string {
+	allowed := stageCodecPreference(conf, stage)
+
+	accepted := parseAcceptEncoding(acceptHeader)
+	if len(accepted) == 0 {
+		return encIdentity
+	}
+
+	rank := func(name string) int {
+		for i, codec := range allowed {
+			if codec == name {
+				return i
+			}
+		}
+		return len(allowed)
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		if accepted[i].q != accepted[j].q {
+			return accepted[i].q > accepted[j].q
+		}
+		return rank(accepted[i].name) < rank(accepted[j].name)
+	})
+
+	for _, a := range accepted {
+		if a.name == "*" {
+			if len(allowed) > 0 {
+				return allowed[0]
+			}
+			return encIdentity
+		}
+		if a.name == encIdentity {
+			return encIdentity
+		}
+		for _, codec := range allowed {
+			if a.name == codec {
+				return codec
+			}
+		}
+	}
+	return encIdentity
+}
+
+// compressedCacheDir returns the root of the on-disk cache of
+// already-compressed artifacts.
+func compressedCacheDir(conf *warewulfconf.ControllerConf, encoding string) string {
+	return path.Join(conf.Paths.LocalStateDir, "warewulf/cache/compressed", encoding)
+}
+
+// cacheKey derives a stable cache key for a source file from its path,
+// size and mtime, so that the cache self-invalidates whenever the
+// underlying artifact changes.
+func cacheKey(srcFile string, info os.FileInfo) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", srcFile, info.Size(), info.ModTime().UnixNano())))
+	return fmt.Sprintf("%x", sum)
+}
+
+// compressToCache returns the path to a version of srcFile compressed
+// with encoding, compressing it on demand and persisting the result
+// under the compressed cache so subsequent requests are served from
+// disk without recompressing.
+func compressToCache(conf *warewulfconf.ControllerConf, srcFile string, encoding string) (string, error) {
+	if encoding == encIdentity {
+		return srcFile, nil
+	}
+
+	// A pre-generated sibling (e.g. produced by `wwctl container repack`)
+	// always takes precedence over anything in the cache.
+	if sibling := srcFile + "." + encodingExt[encoding]; util.IsFile(sibling) {
+		return sibling, nil
+	}
+
+	info, err := os.Stat(srcFile)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir := compressedCacheDir(conf, encoding)
+	cachedFile := path.Join(cacheDir, cacheKey(srcFile, info))
+	if util.IsFile(cachedFile) {
+		// Bump the entry's mtime so PruneCache's LRU eviction sees it as
+		// recently used instead of evicting it purely by creation order.
+		now := time.Now()
+		if err := os.Chtimes(cachedFile, now, now); err != nil {
+			wwlog.Warn("could not update access time for cache entry %s: %s", cachedFile, err)
+		}
+		return cachedFile, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("could not create compressed cache dir %s: %w", cacheDir, err)
+	}
+
+	src, err := os.Open(srcFile)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	tmpFile, err := os.CreateTemp(cacheDir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if err := compressStream(encoding, src, tmpFile); err != nil {
+		tmpFile.Close()
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpPath, cachedFile); err != nil {
+		return "", fmt.Errorf("could not finalize compressed cache entry %s: %w", cachedFile, err)
+	}
+
+	wwlog.Debug("compressed %s to %s cache entry %s", srcFile, encoding, cachedFile)
+	return cachedFile, nil
+}
+
+// compressBuffer compresses an in-memory buffer (used for rendered
+// templates, which have no on-disk sibling to cache) and returns the
+// compressed bytes.
+func compressBuffer(encoding string, data []byte) ([]byte, error) {
+	var buf strings.Builder
+	if err := compressStream(encoding, strings.NewReader(string(data)), &buf); err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
+
+// compressStream streams src through the requested encoding into dst.
+func compressStream(encoding string, src io.Reader, dst io.Writer) error {
+	switch encoding {
+	case encGzip:
+		gw := gzip.NewWriter(dst)
+		if _, err := io.Copy(gw, src); err != nil {
+			gw.Close()
+			return err
+		}
+		return gw.Close()
+	case encZstd:
+		zw, err := zstd.NewWriter(dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(zw, src); err != nil {
+			zw.Close()
+			return err
+		}
+		return zw.Close()
+	default:
+		return fmt.Errorf("unsupported compression encoding: %s", encoding)
+	}
+}
+
+// PruneCache evicts the least-recently-used entries from the compressed
+// artifact cache until its total size is at or under maxBytes. It backs
+// the `wwctl cache prune` command.
+func PruneCache(conf *warewulfconf.ControllerConf, maxBytes int64) error {
+	root := path.Join(conf.Paths.LocalStateDir, "warewulf/cache/compressed")
+
+	type entry struct {
+		path    string
+		size    int64
+		accessT int64
+	}
+	var entries []entry
+	var total int64
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		entries = append(entries, entry{path: p, size: info.Size(), accessT: info.ModTime().UnixNano()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not walk compressed cache: %w", err)
+	}
+
+	if total <= maxBytes {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].accessT < entries[j].accessT })
+
+	evicted := 0
+	for _, e := range entries {
+		if total <= maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil {
+			wwlog.Error("could not evict cache entry %s: %s", e.path, err)
+			continue
+		}
+		total -= e.size
+		evicted++
+	}
+
+	wwlog.Info("pruned %d compressed cache entries, %d bytes remaining", evicted, total)
+	return nil
+}
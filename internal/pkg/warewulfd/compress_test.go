@@ -0,0 +1,74 @@
+package warewulfd
+
+import (
+	"reflect"
+	"testing"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+)
+
+func TestParseAcceptEncodingOrdersByQValue(t *testing.T) {
+	got := parseAcceptEncoding("gzip;q=0.5, zstd;q=0.9, identity")
+	want := []string{"identity", "zstd", "gzip"}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseAcceptEncoding = %+v, want %d entries", got, len(want))
+	}
+	for i, name := range want {
+		if got[i].name != name {
+			t.Errorf("entry %d = %q, want %q", i, got[i].name, name)
+		}
+	}
+}
+
+func TestParseAcceptEncodingSkipsZeroQAndEmpty(t *testing.T) {
+	if got := parseAcceptEncoding(""); got != nil {
+		t.Fatalf("parseAcceptEncoding(\"\") = %+v, want nil", got)
+	}
+
+	got := parseAcceptEncoding("gzip;q=0, zstd")
+	if len(got) != 1 || got[0].name != "zstd" {
+		t.Fatalf("parseAcceptEncoding = %+v, want only zstd", got)
+	}
+}
+
+func TestNegotiateEncodingPrefersClientOrderWithinServerPolicy(t *testing.T) {
+	if got := negotiateEncoding(nil, "system", "gzip;q=1.0, zstd;q=0.8"); got != encGzip {
+		t.Errorf("negotiateEncoding = %q, want %q", got, encGzip)
+	}
+	if got := negotiateEncoding(nil, "system", "zstd"); got != encZstd {
+		t.Errorf("negotiateEncoding = %q, want %q", got, encZstd)
+	}
+	if got := negotiateEncoding(nil, "system", ""); got != encIdentity {
+		t.Errorf("negotiateEncoding with no header = %q, want %q", got, encIdentity)
+	}
+	if got := negotiateEncoding(nil, "system", "br"); got != encIdentity {
+		t.Errorf("negotiateEncoding with unsupported codec = %q, want %q", got, encIdentity)
+	}
+}
+
+func TestNegotiateEncodingHonorsStageOverrideFromConfig(t *testing.T) {
+	conf := &warewulfconf.ControllerConf{}
+	conf.Warewulf.CompressStages = map[string]string{"system": encIdentity}
+
+	if got := negotiateEncoding(conf, "system", "zstd, gzip"); got != encIdentity {
+		t.Errorf("negotiateEncoding with identity override = %q, want %q", got, encIdentity)
+	}
+
+	conf.Warewulf.CompressStages["system"] = encZstd
+	if got := negotiateEncoding(conf, "system", "gzip, zstd"); got != encZstd {
+		t.Errorf("negotiateEncoding with zstd override = %q, want %q", got, encZstd)
+	}
+}
+
+func TestStageCodecPreferenceFallsBackToDefault(t *testing.T) {
+	got := stageCodecPreference(nil, "ipxe")
+	want := defaultStageCodecs["ipxe"]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stageCodecPreference(nil, \"ipxe\") = %v, want %v", got, want)
+	}
+
+	if got := stageCodecPreference(nil, "unknown-stage"); got != nil {
+		t.Errorf("stageCodecPreference for unlisted stage = %v, want nil", got)
+	}
+}
@@ -0,0 +1,103 @@
+package warewulfd
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"syscall"
+	"time"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+	"github.com/warewulf/warewulf/internal/pkg/node"
+	"github.com/warewulf/warewulf/internal/pkg/provisionstatus"
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// provisionStatusFile is where the in-memory provisioning status ring is
+// persisted across restarts.
+func provisionStatusFile(conf *warewulfconf.ControllerConf) string {
+	return path.Join(conf.Paths.LocalStateDir, "warewulf/provisionstatus.json")
+}
+
+// RegisterRoutes mounts the live-status and overlay build metrics
+// endpoints added alongside ProvisionSend. Call it from the daemon's HTTP
+// server setup next to wherever ProvisionSend itself is already
+// registered.
+func RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/status/events", StatusEvents)
+	mux.HandleFunc("/status/history", StatusHistory)
+	mux.HandleFunc("/metrics", OverlayMetrics)
+}
+
+// overlayPrewarmInterval is how often the prewarm loop re-scans the node
+// list for overlays that need rebuilding ahead of a boot.
+const overlayPrewarmInterval = 30 * time.Second
+
+// StartBackgroundTasks loads any provisioning status history persisted by
+// a previous run, starts the overlay prewarm loop for both the system and
+// runtime overlay contexts, and returns a shutdown function the caller
+// must invoke (e.g. from its signal handler) before the process exits so
+// recent history is persisted for the next start and the prewarm loop
+// stops cleanly.
+//
+// listNodes is supplied by the caller rather than resolved here since
+// warewulfd doesn't otherwise depend on how the node database is loaded.
+func StartBackgroundTasks(conf *warewulfconf.ControllerConf, listNodes func() ([]node.Node, error)) (shutdown func()) {
+	historyFile := provisionStatusFile(conf)
+	if err := provisionstatus.Default.LoadFromDisk(historyFile); err != nil {
+		wwlog.Error("could not load persisted provisioning status from %s: %s", historyFile, err)
+	}
+
+	stop := make(chan struct{})
+	coordinator := getOverlayCoordinator(conf)
+	go coordinator.Prewarm(listNodes, nodeOverlayInputs(conf, "system"), overlayPrewarmInterval, stop)
+	go coordinator.Prewarm(listNodes, nodeOverlayInputs(conf, "runtime"), overlayPrewarmInterval, stop)
+
+	return func() {
+		close(stop)
+		if err := provisionstatus.Default.SaveToDisk(historyFile); err != nil {
+			wwlog.Error("could not persist provisioning status to %s: %s", historyFile, err)
+		}
+	}
+}
+
+// RunServer mounts ProvisionSend and the routes added by RegisterRoutes
+// on a fresh mux, starts the background tasks (status history
+// persistence, overlay prewarm), and blocks serving HTTP on conf's
+// configured port until the process receives SIGINT/SIGTERM, at which
+// point it shuts the background tasks down before returning.
+//
+// This is the entry point cmd/warewulfd's main should call; it exists so
+// ProvisionSend, the live-status endpoints and the overlay cache metrics
+// are reachable from a running daemon instead of only from tests.
+func RunServer(conf *warewulfconf.ControllerConf, listNodes func() ([]node.Node, error)) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", ProvisionSend)
+	RegisterRoutes(mux)
+
+	shutdownBackgroundTasks := StartBackgroundTasks(conf, listNodes)
+	defer shutdownBackgroundTasks()
+
+	server := &http.Server{
+		Addr:    ":" + strconv.Itoa(conf.Warewulf.Port),
+		Handler: mux,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-sigs:
+		wwlog.Info("warewulfd: shutting down")
+		return server.Close()
+	}
+}
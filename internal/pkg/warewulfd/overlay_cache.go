@@ -0,0 +1,127 @@
+package warewulfd
+
+import (
+	"net/http"
+	"path"
+	"runtime"
+	"sync"
+
+	warewulfconf "github.com/warewulf/warewulf/internal/pkg/config"
+	"github.com/warewulf/warewulf/internal/pkg/container"
+	"github.com/warewulf/warewulf/internal/pkg/node"
+	"github.com/warewulf/warewulf/internal/pkg/overlay"
+)
+
+var (
+	overlayCoordinatorOnce sync.Once
+	overlayCoordinator     *overlay.Coordinator
+	overlayWorkersMu       sync.Mutex
+	overlayWorkersApplied  int
+)
+
+// getOverlayCoordinator lazily builds the process-wide overlay build
+// coordinator, bounding concurrent overlay builds to the configured
+// worker count (one per CPU by default, see overlayBuildWorkers) so that
+// a boot storm of identical nodes can't fork-bomb warewulfd.
+func getOverlayCoordinator(conf *warewulfconf.ControllerConf) *overlay.Coordinator {
+	overlayCoordinatorOnce.Do(func() {
+		cacheDir := path.Join(conf.Paths.LocalStateDir, "warewulf/overlays/cache")
+		overlayCoordinator = overlay.NewCoordinator(
+			func(n node.Node, context string, overlayNames []string) (string, error) {
+				return getOverlayFile(n, context, overlayNames, conf.Warewulf.AutobuildOverlays)
+			},
+			cacheDir,
+			overlayBuildWorkers(conf),
+		)
+		overlayWorkersApplied = overlayBuildWorkers(conf)
+	})
+
+	// conf is re-fetched on every request; pick up a changed worker count
+	// from a reloaded warewulf.conf without waiting for a daemon restart.
+	// Resize() replaces the semaphore, so only call it when the value
+	// actually changed or it would reset capacity tracking for builds
+	// that are already in flight on every single request.
+	if workers := overlayBuildWorkers(conf); workers != overlayWorkersApplied {
+		overlayWorkersMu.Lock()
+		if workers != overlayWorkersApplied {
+			overlayCoordinator.Resize(workers)
+			overlayWorkersApplied = workers
+		}
+		overlayWorkersMu.Unlock()
+	}
+
+	return overlayCoordinator
+}
+
+// overlayBuildWorkers returns the configured overlay build concurrency,
+// defaulting to one worker per CPU when warewulf.conf leaves it unset.
+func overlayBuildWorkers(conf *warewulfconf.ControllerConf) int {
+	if conf.Warewulf.OverlayBuildWorkers > 0 {
+		return conf.Warewulf.OverlayBuildWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// buildOverlayCached serves a (possibly cached) overlay image for
+// remoteNode, sharing a single build across concurrent requests for the
+// same node/context/overlay combination and short-circuiting straight to
+// the cached file when its inputs (overlay source files, the node
+// definition, the container image) haven't changed since it was built.
+func buildOverlayCached(conf *warewulfconf.ControllerConf, remoteNode node.Node, context string, overlayNames []string) (string, error) {
+	coordinator := getOverlayCoordinator(conf)
+
+	names, contentPaths := overlayBuildInputs(conf, remoteNode, context, overlayNames)
+	hash := overlay.InputHash(remoteNode.Id(), context, names, contentPaths)
+
+	return coordinator.Build(remoteNode, context, overlayNames, hash)
+}
+
+// overlayBuildInputs resolves the effective overlay name list (falling
+// back to the node's configured system/runtime overlays when the caller
+// didn't request explicit ones) and the paths whose mtime+size feed the
+// build cache's content hash: the overlay source directories, the shared
+// node definitions file, and the node's container image.
+func overlayBuildInputs(conf *warewulfconf.ControllerConf, n node.Node, context string, overlayNames []string) (names []string, contentPaths []string) {
+	names = overlayNames
+	if len(names) == 0 {
+		switch context {
+		case "system":
+			names = n.SystemOverlay
+		case "runtime":
+			names = n.RuntimeOverlay
+		}
+	}
+
+	contentPaths = []string{
+		container.ImageFile(n.ContainerName),
+		// The shared node definitions file: any edit to a node's YAML
+		// (overlay list, container, tags, ...) must invalidate every
+		// cached overlay image, not just the ones rebuilt from scratch.
+		path.Join(conf.Paths.Sysconfdir, "warewulf/nodes.conf"),
+	}
+	for _, name := range names {
+		contentPaths = append(contentPaths, overlay.OverlaySourceDir(conf, name))
+	}
+	return names, contentPaths
+}
+
+// nodeOverlayInputs returns the overlay.NodeInputs builder the prewarm
+// loop uses to rebuild every node's overlay for the given context ahead
+// of the first PXE request.
+func nodeOverlayInputs(conf *warewulfconf.ControllerConf, context string) func(node.Node) overlay.NodeInputs {
+	return func(n node.Node) overlay.NodeInputs {
+		names, contentPaths := overlayBuildInputs(conf, n, context, nil)
+		return overlay.NodeInputs{
+			Context:      context,
+			OverlayNames: names,
+			ContentPaths: contentPaths,
+		}
+	}
+}
+
+// OverlayMetrics implements GET /metrics, exposing the overlay build
+// coordinator's cache hit/miss/inflight/eviction counters for Prometheus
+// scraping.
+func OverlayMetrics(w http.ResponseWriter, req *http.Request) {
+	getOverlayCoordinator(warewulfconf.Get()).ServeMetrics(w, req)
+}
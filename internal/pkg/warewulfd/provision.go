@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path"
 	"path/filepath"
 	"strconv"
@@ -17,10 +18,27 @@ import (
 	"github.com/warewulf/warewulf/internal/pkg/kernel"
 	"github.com/warewulf/warewulf/internal/pkg/node"
 	"github.com/warewulf/warewulf/internal/pkg/overlay"
+	"github.com/warewulf/warewulf/internal/pkg/provisionstatus"
 	"github.com/warewulf/warewulf/internal/pkg/util"
 	"github.com/warewulf/warewulf/internal/pkg/wwlog"
 )
 
+// recordStatus stamps the legacy per-node status used by `wwctl node status`
+// and appends the transition to the in-memory provisioning status ring so
+// `wwctl status watch` and the /status/events subscribers see it live.
+// bytesSent is 0 for transitions that never sent a file body.
+func recordStatus(nodeId, stage, status, ipaddr, artifact string, bytesSent int64) {
+	updateStatus(nodeId, stage, status, ipaddr)
+	provisionstatus.Record(provisionstatus.Event{
+		NodeId:    nodeId,
+		Stage:     stage,
+		Status:    status,
+		SourceIP:  ipaddr,
+		Artifact:  artifact,
+		BytesSent: bytesSent,
+	})
+}
+
 type templateVars struct {
 	Message        string
 	WaitTime       string
@@ -69,6 +87,10 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 
 	status_stage := status_stages[rinfo.stage]
 	var stage_file string
+	// preEncoded names the Content-Encoding already baked into stage_file
+	// (e.g. a pre-repacked zstd initramfs/container image), so the final
+	// dispatch can skip transfer-encoding negotiation for it entirely.
+	var preEncoded string
 
 	// TODO: when module version is upgraded to go1.18, should be 'any' type
 	var tmpl_data interface{}
@@ -83,7 +105,7 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 	if remoteNode.AssetKey != "" && remoteNode.AssetKey != rinfo.assetkey {
 		w.WriteHeader(http.StatusUnauthorized)
 		wwlog.Denied("Incorrect asset key for node: %s", remoteNode.Id())
-		updateStatus(remoteNode.Id(), status_stage, "BAD_ASSET", rinfo.ipaddr)
+		recordStatus(remoteNode.Id(), status_stage, "BAD_ASSET", rinfo.ipaddr, "", 0)
 		return
 	}
 
@@ -131,7 +153,21 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 
 	} else if rinfo.stage == "container" {
 		if remoteNode.ContainerName != "" {
-			stage_file = container.ImageFile(remoteNode.ContainerName)
+			acceptHeader := req.Header.Get("Accept-Encoding")
+			codec := container.ParseCodec(negotiateEncoding(conf, rinfo.stage, acceptHeader))
+
+			// An operator-pinned codec (see `wwctl container repack
+			// --pin`) only applies when the client actually advertised
+			// support for it; otherwise we'd serve a codec the client
+			// never said it could decode.
+			if pinned, ok := container.PreferredCodec(remoteNode.ContainerName); ok && clientAccepts(acceptHeader, string(pinned)) {
+				codec = pinned
+			}
+
+			stage_file = container.ImageFileFor(remoteNode.ContainerName, codec)
+			if codec == container.CodecZstd && strings.HasSuffix(stage_file, ".zst") {
+				preEncoded = encZstd
+			}
 		} else {
 			wwlog.Warn("No container set for node %s", remoteNode.Id())
 		}
@@ -145,11 +181,7 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 		} else {
 			context = rinfo.stage
 		}
-		stage_file, err = getOverlayFile(
-			remoteNode,
-			context,
-			request_overlays,
-			conf.Warewulf.AutobuildOverlays)
+		stage_file, err = buildOverlayCached(conf, remoteNode, context, request_overlays)
 
 		if err != nil {
 			if errors.Is(err, overlay.ErrDoesNotExist) {
@@ -227,10 +259,14 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 			if err != nil {
 				wwlog.Error("No kernel found for initramfs for container %s: %s", remoteNode.ContainerName, err)
 			}
-			stage_file, err = container.InitramfsBootPath(remoteNode.ContainerName, kver)
+			codec := container.ParseCodec(negotiateEncoding(conf, rinfo.stage, req.Header.Get("Accept-Encoding")))
+			stage_file, err = container.InitramfsBootPathFor(remoteNode.ContainerName, kver, codec)
 			if err != nil {
 				wwlog.Error("No initramfs found for container %s: %s", remoteNode.ContainerName, err)
 			}
+			if codec == container.CodecZstd && strings.HasSuffix(stage_file, ".zst") {
+				preEncoded = encZstd
+			}
 		} else {
 			wwlog.Warn("No container set for node %s", remoteNode.Id())
 		}
@@ -239,15 +275,9 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 	wwlog.Serv("stage_file '%s'", stage_file)
 
 	if util.IsFile(stage_file) {
+		var bytesSent int64
 
 		if tmpl_data != nil {
-			if rinfo.compress != "" {
-				wwlog.Error("Unsupported %s compressed version for file: %s",
-					rinfo.compress, stage_file)
-				w.WriteHeader(http.StatusNotFound)
-				return
-			}
-
 			// Create a template with the Sprig functions.
 			tmpl := template.New(filepath.Base(stage_file)).Funcs(sprig.TxtFuncMap())
 
@@ -269,49 +299,93 @@ func ProvisionSend(w http.ResponseWriter, req *http.Request) {
 				return
 			}
 
+			body := buf.Bytes()
+			encoding := negotiateEncoding(conf, rinfo.stage, req.Header.Get("Accept-Encoding"))
+			if encoding != encIdentity {
+				compressed, err := compressBuffer(encoding, body)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					wwlog.ErrorExc(err, "")
+					return
+				}
+				body = compressed
+				w.Header().Set("Content-Encoding", encoding)
+			}
+
 			w.Header().Set("Content-Type", "text")
-			w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
-			_, err = buf.WriteTo(w)
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			_, err = w.Write(body)
 			if err != nil {
 				wwlog.ErrorExc(err, "")
 			}
+			bytesSent = int64(len(body))
 
 			wwlog.Send("%15s: %s", remoteNode.Id(), stage_file)
 
 		} else {
-			if rinfo.compress == "gz" {
-				stage_file += ".gz"
+			sendStage := stage_file
+
+			if preEncoded != "" {
+				// stage_file was already resolved to a pre-repacked
+				// variant (e.g. container.ImageFileFor/InitramfsBootPathFor
+				// picking a .zst artifact); nothing left to negotiate.
+				w.Header().Set("Content-Encoding", preEncoded)
+			} else {
+				encoding := negotiateEncoding(conf, rinfo.stage, req.Header.Get("Accept-Encoding"))
+
+				if rinfo.compress == "gz" {
+					// Explicit legacy override: always honored verbatim.
+					encoding = encGzip
+				}
 
-				if !util.IsFile(stage_file) {
-					wwlog.Error("unprepared for compressed version of file %s",
-						stage_file)
-					w.WriteHeader(http.StatusNotFound)
-					return
+				if encoding != encIdentity {
+					compressed, err := compressToCache(conf, stage_file, encoding)
+					if err != nil {
+						wwlog.ErrorExc(err, "")
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					sendStage = compressed
+					w.Header().Set("Content-Encoding", encoding)
 				}
-			} else if rinfo.compress != "" {
-				wwlog.Error("unsupported %s compressed version of file %s",
-					rinfo.compress, stage_file)
-				w.WriteHeader(http.StatusNotFound)
 			}
 
-			err = sendFile(w, req, stage_file, remoteNode.Id())
+			err = sendFile(w, req, sendStage, remoteNode.Id())
 			if err != nil {
 				wwlog.ErrorExc(err, "")
 				return
 			}
+			if info, err := os.Stat(sendStage); err == nil {
+				bytesSent = info.Size()
+			}
 		}
 
-		updateStatus(remoteNode.Id(), status_stage, path.Base(stage_file), rinfo.ipaddr)
+		recordStatus(remoteNode.Id(), status_stage, "SENT", rinfo.ipaddr, path.Base(stage_file), bytesSent)
 
 	} else if stage_file == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		wwlog.Error("No resource selected")
-		updateStatus(remoteNode.Id(), status_stage, "BAD_REQUEST", rinfo.ipaddr)
+		recordStatus(remoteNode.Id(), status_stage, "BAD_REQUEST", rinfo.ipaddr, "", 0)
 
 	} else {
 		w.WriteHeader(http.StatusNotFound)
 		wwlog.Error("Not found: %s", stage_file)
-		updateStatus(remoteNode.Id(), status_stage, "NOT_FOUND", rinfo.ipaddr)
+		recordStatus(remoteNode.Id(), status_stage, "NOT_FOUND", rinfo.ipaddr, path.Base(stage_file), 0)
 	}
 
 }
+
+// clientAccepts reports whether acceptHeader (a request's raw
+// Accept-Encoding value) actually lists name, or a wildcard, among the
+// encodings the client is willing to decode. It's used to gate an
+// operator-pinned container codec against what the client advertised,
+// since a pin is only a hint and must not override a client that never
+// said it could decode the pinned codec.
+func clientAccepts(acceptHeader, name string) bool {
+	for _, a := range parseAcceptEncoding(acceptHeader) {
+		if a.name == name || a.name == "*" {
+			return true
+		}
+	}
+	return false
+}
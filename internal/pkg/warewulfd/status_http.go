@@ -0,0 +1,113 @@
+package warewulfd
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/warewulf/warewulf/internal/pkg/provisionstatus"
+	"github.com/warewulf/warewulf/internal/pkg/wwlog"
+)
+
+// StatusEvents implements GET /status/events, a Server-Sent Events
+// stream of provisioning stage transitions, optionally filtered by
+// ?node=, ?stage= and backfilled from ?since=<rfc3339>.
+func StatusEvents(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		wwlog.Error("status: ResponseWriter does not support flushing")
+		return
+	}
+
+	node := req.URL.Query().Get("node")
+	stage := req.URL.Query().Get("stage")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// Subscribe before replaying history: anything recorded between the
+	// Since() snapshot below and this call would otherwise fall in the
+	// gap between "already replayed" and "not yet subscribed" and be
+	// silently dropped.
+	events, cancel := provisionstatus.Default.Subscribe()
+	defer cancel()
+
+	var lastReplayed time.Time
+	if since := req.URL.Query().Get("since"); since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			for _, e := range provisionstatus.Default.Since(t) {
+				if e.Matches(node, stage) {
+					writeSSEEvent(w, e)
+				}
+				if e.Time.After(lastReplayed) {
+					lastReplayed = e.Time
+				}
+			}
+			flusher.Flush()
+		} else {
+			wwlog.Warn("status: invalid since=%s: %s", since, err)
+		}
+	}
+
+	for {
+		select {
+		case e := <-events:
+			if !e.Time.After(lastReplayed) {
+				// Already delivered as part of the history replay above.
+				continue
+			}
+			if e.Matches(node, stage) {
+				writeSSEEvent(w, e)
+				flusher.Flush()
+			}
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, e provisionstatus.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		wwlog.ErrorExc(err, "")
+		return
+	}
+	_, err = w.Write([]byte("data: " + string(data) + "\n\n"))
+	if err != nil {
+		wwlog.ErrorExc(err, "")
+	}
+}
+
+// StatusHistory implements GET /status/history?since=<rfc3339>, returning
+// every recorded transition newer than since as a JSON array so a
+// reconnecting client can catch up without gaps before switching to
+// StatusEvents.
+func StatusHistory(w http.ResponseWriter, req *http.Request) {
+	since := time.Time{}
+	if s := req.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			wwlog.ErrorExc(err, "status: invalid since")
+			return
+		}
+		since = t
+	}
+
+	node := req.URL.Query().Get("node")
+	stage := req.URL.Query().Get("stage")
+
+	matched := []provisionstatus.Event{}
+	for _, e := range provisionstatus.Default.Since(since) {
+		if e.Matches(node, stage) {
+			matched = append(matched, e)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(matched); err != nil {
+		wwlog.ErrorExc(err, "")
+	}
+}